@@ -0,0 +1,40 @@
+package config
+
+import (
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// PluginSettings maps a plugin ID to its [plugin.<id>] ini section.
+type PluginSettings map[string]map[string]string
+
+// Cfg is the configuration consumed by the plugins package, derived from
+// the global Grafana configuration.
+type Cfg struct {
+	DataPath string
+
+	PluginSettings PluginSettings
+
+	// PluginsCDNURLTemplate is the base URL template of the primary plugins
+	// CDN. An empty value disables the CDN.
+	PluginsCDNURLTemplate string
+	// PluginsCDNMirrorURLTemplate is the base URL template of a secondary
+	// plugins CDN used as a fallback when the primary CDN is unreachable or
+	// serves an asset that fails digest verification. An empty value
+	// disables the mirror fallback.
+	PluginsCDNMirrorURLTemplate string
+}
+
+// NewCfg derives the plugins Cfg from the global Grafana configuration.
+func NewCfg(grafanaCfg *setting.Cfg) *Cfg {
+	return &Cfg{
+		DataPath:                    grafanaCfg.DataPath,
+		PluginSettings:              PluginSettings(grafanaCfg.PluginSettings),
+		PluginsCDNURLTemplate:       grafanaCfg.PluginsCDNURLTemplate,
+		PluginsCDNMirrorURLTemplate: grafanaCfg.PluginsCDNMirrorURLTemplate,
+	}
+}
+
+// ProvideCfg is the wire provider for Cfg.
+func ProvideCfg(grafanaCfg *setting.Cfg) *Cfg {
+	return NewCfg(grafanaCfg)
+}
@@ -0,0 +1,293 @@
+package pluginscdn
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manifestFileName is the file shipped alongside a CDN plugin that maps its
+// asset paths to their expected digests.
+const manifestFileName = "plugin-cdn-manifest.json"
+
+// ErrDigestMismatch is returned when a fetched asset doesn't match the
+// digest recorded in its plugin's manifest.
+var ErrDigestMismatch = func(assetPath string) error {
+	return fmt.Errorf("digest mismatch for asset %q", assetPath)
+}
+
+// manifestEntry is the expected digest of a single CDN asset, as recorded in
+// a plugin's plugin-cdn-manifest.json.
+type manifestEntry struct {
+	Algorithm string `json:"algorithm"`
+	Digest    string `json:"digest"`
+}
+
+// manifest maps an asset path, relative to the plugin's CDN base path, to
+// its expected digest.
+type manifest map[string]manifestEntry
+
+// AssetURLWithIntegrity returns the URL of a CDN asset for a CDN plugin
+// together with its Subresource Integrity string, in the
+// "<algorithm>-<base64 digest>" format expected by
+// <script integrity="sha384-...">. If the specified plugin is not a CDN
+// plugin, it returns ErrPluginNotCDN.
+func (s *Service) AssetURLWithIntegrity(ctx context.Context, pluginID, pluginVersion, assetPath string) (string, string, error) {
+	if !s.PluginSupported(pluginID) {
+		return "", "", ErrPluginNotCDN
+	}
+
+	assetURL, err := s.NewCDNURLConstructor(pluginID, pluginVersion).StringPath(assetPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	m, err := s.loadManifest(ctx, pluginID, pluginVersion)
+	if err != nil {
+		return "", "", err
+	}
+	entry, ok := m[assetPath]
+	if !ok {
+		return "", "", fmt.Errorf("no manifest entry for asset %q", assetPath)
+	}
+
+	return assetURL, entry.Algorithm + "-" + entry.Digest, nil
+}
+
+// FetchAsset streams a CDN asset for a CDN plugin, verifying it against the
+// digest recorded in the plugin's manifest. If the primary CDN URL fails or
+// its digest doesn't match, it transparently falls back to the configured
+// mirror CDN and finally to the on-disk cache.
+func (s *Service) FetchAsset(ctx context.Context, pluginID, pluginVersion, assetPath string) (io.ReadCloser, error) {
+	if !s.PluginSupported(pluginID) {
+		return nil, ErrPluginNotCDN
+	}
+
+	m, err := s.loadManifest(ctx, pluginID, pluginVersion)
+	if err != nil {
+		return nil, err
+	}
+	entry, ok := m[assetPath]
+	if !ok {
+		return nil, fmt.Errorf("no manifest entry for asset %q", assetPath)
+	}
+
+	primaryURL, err := s.NewCDNURLConstructor(pluginID, pluginVersion).StringPath(assetPath)
+	if err == nil {
+		if data, err := s.fetchAndVerify(ctx, primaryURL, entry); err == nil {
+			s.writeLocalCache(pluginID, pluginVersion, assetPath, data)
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+
+	if mirrorTemplate := s.cfg.PluginsCDNMirrorURLTemplate; mirrorTemplate != "" {
+		mirrorURL, err := (URLConstructor{
+			cdnURLTemplate: mirrorTemplate,
+			pluginID:       pluginID,
+			pluginVersion:  pluginVersion,
+		}).StringPath(assetPath)
+		if err == nil {
+			if data, err := s.fetchAndVerify(ctx, mirrorURL, entry); err == nil {
+				s.writeLocalCache(pluginID, pluginVersion, assetPath, data)
+				return io.NopCloser(bytes.NewReader(data)), nil
+			}
+		}
+	}
+
+	return s.openLocalCache(pluginID, pluginVersion, assetPath)
+}
+
+// Verify reports whether digest -- the digest of bytes a caller already has
+// in hand -- matches the manifest entry for assetPath. It returns
+// ErrDigestMismatch if it doesn't.
+func (s *Service) Verify(ctx context.Context, pluginID, pluginVersion, assetPath, digest string) error {
+	m, err := s.loadManifest(ctx, pluginID, pluginVersion)
+	if err != nil {
+		return err
+	}
+	entry, ok := m[assetPath]
+	if !ok {
+		return fmt.Errorf("no manifest entry for asset %q", assetPath)
+	}
+	if digest != entry.Digest {
+		return ErrDigestMismatch(assetPath)
+	}
+	return nil
+}
+
+// fetchAndVerify downloads assetURL and checks it against entry's digest,
+// discarding the buffered bytes on mismatch.
+func (s *Service) fetchAndVerify(ctx context.Context, assetURL string, entry manifestEntry) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching %s", resp.StatusCode, assetURL)
+	}
+
+	h, err := newDigestHash(entry.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(io.MultiWriter(&buf, h), resp.Body); err != nil {
+		return nil, err
+	}
+
+	if digest := base64.StdEncoding.EncodeToString(h.Sum(nil)); digest != entry.Digest {
+		return nil, ErrDigestMismatch(assetURL)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newDigestHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha384":
+		return sha512.New384(), nil
+	case "sha256":
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+}
+
+// loadManifest returns the parsed plugin-cdn-manifest.json for the given
+// plugin and version, serving it from the in-memory cache when present and
+// otherwise fetching it through the same primary -> mirror -> local cache
+// chain used for assets.
+func (s *Service) loadManifest(ctx context.Context, pluginID, pluginVersion string) (manifest, error) {
+	key := pluginID + "@" + pluginVersion
+	if m, ok := s.manifests.get(key); ok {
+		return m, nil
+	}
+
+	data, err := s.fetchManifestBytes(ctx, pluginID, pluginVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("decode manifest for %s@%s: %w", pluginID, pluginVersion, err)
+	}
+
+	s.manifests.add(key, m)
+	return m, nil
+}
+
+// fetchManifestBytes fetches plugin-cdn-manifest.json from the primary CDN,
+// falling back to the mirror CDN and then the on-disk cache, caching a
+// fresh copy on disk whenever it's fetched remotely.
+func (s *Service) fetchManifestBytes(ctx context.Context, pluginID, pluginVersion string) ([]byte, error) {
+	if manifestURL, err := s.NewCDNURLConstructor(pluginID, pluginVersion).StringPath(manifestFileName); err == nil {
+		if data, err := s.fetchRaw(ctx, manifestURL); err == nil {
+			s.writeLocalCache(pluginID, pluginVersion, manifestFileName, data)
+			return data, nil
+		}
+	}
+
+	if mirrorTemplate := s.cfg.PluginsCDNMirrorURLTemplate; mirrorTemplate != "" {
+		mirrorURL, err := (URLConstructor{
+			cdnURLTemplate: mirrorTemplate,
+			pluginID:       pluginID,
+			pluginVersion:  pluginVersion,
+		}).StringPath(manifestFileName)
+		if err == nil {
+			if data, err := s.fetchRaw(ctx, mirrorURL); err == nil {
+				s.writeLocalCache(pluginID, pluginVersion, manifestFileName, data)
+				return data, nil
+			}
+		}
+	}
+
+	f, err := s.openLocalCache(pluginID, pluginVersion, manifestFileName)
+	if err != nil {
+		return nil, fmt.Errorf("manifest for %s@%s unavailable from cdn, mirror or local cache", pluginID, pluginVersion)
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(f)
+}
+
+// fetchRaw performs a plain GET, used for the manifest itself, which has no
+// digest of its own to verify against.
+func (s *Service) fetchRaw(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching %s", resp.StatusCode, url)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// pluginCDNCacheRoot is the on-disk directory all cached plugin CDN assets
+// live under, rooted at the configured data path.
+func (s *Service) pluginCDNCacheRoot() string {
+	return filepath.Join(s.cfg.DataPath, "plugin-cdn-cache")
+}
+
+// localCachePath joins pluginID, pluginVersion and assetPath onto the cache
+// root and rejects the result if it escapes that root, e.g. via ".."
+// segments smuggled into any of the three.
+func (s *Service) localCachePath(pluginID, pluginVersion, assetPath string) (string, error) {
+	root := s.pluginCDNCacheRoot()
+	dst := filepath.Join(root, pluginID, pluginVersion, filepath.FromSlash(assetPath))
+
+	rel, err := filepath.Rel(root, dst)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path escapes plugin cdn cache directory: plugin %q version %q asset %q", pluginID, pluginVersion, assetPath)
+	}
+	return dst, nil
+}
+
+func (s *Service) writeLocalCache(pluginID, pluginVersion, assetPath string, data []byte) {
+	dst, err := s.localCachePath(pluginID, pluginVersion, assetPath)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return
+	}
+	_ = os.WriteFile(dst, data, 0640)
+}
+
+func (s *Service) openLocalCache(pluginID, pluginVersion, assetPath string) (io.ReadCloser, error) {
+	src, err := s.localCachePath(pluginID, pluginVersion, assetPath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("asset %q unavailable from cdn, mirror or local cache: %w", assetPath, err)
+	}
+	return f, nil
+}
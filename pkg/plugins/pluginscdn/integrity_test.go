@@ -0,0 +1,98 @@
+package pluginscdn
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/plugins/config"
+)
+
+func TestNewDigestHash(t *testing.T) {
+	_, err := newDigestHash("sha384")
+	require.NoError(t, err)
+
+	_, err = newDigestHash("sha256")
+	require.NoError(t, err)
+
+	_, err = newDigestHash("md5")
+	require.Error(t, err)
+}
+
+func TestService_FetchAndVerify(t *testing.T) {
+	const body = "console.log('plugin asset')"
+	h := sha512.New384()
+	h.Write([]byte(body))
+	digest := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	s := &Service{httpClient: http.DefaultClient}
+
+	data, err := s.fetchAndVerify(context.Background(), ts.URL, manifestEntry{Algorithm: "sha384", Digest: digest})
+	require.NoError(t, err)
+	require.Equal(t, body, string(data))
+
+	_, err = s.fetchAndVerify(context.Background(), ts.URL, manifestEntry{Algorithm: "sha384", Digest: "not-the-right-digest"})
+	require.ErrorContains(t, err, "digest mismatch")
+}
+
+func TestService_FetchRaw(t *testing.T) {
+	const body = `{"module.js":{"algorithm":"sha384","digest":"expected-digest"}}`
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	s := &Service{httpClient: http.DefaultClient}
+
+	data, err := s.fetchRaw(context.Background(), ts.URL)
+	require.NoError(t, err)
+	require.Equal(t, body, string(data))
+}
+
+func TestService_LocalCachePath_RejectsTraversal(t *testing.T) {
+	s := &Service{cfg: &config.Cfg{DataPath: t.TempDir()}}
+
+	_, err := s.localCachePath("my-plugin", "1.0.0", "../../../etc/passwd")
+	require.Error(t, err)
+
+	_, err = s.localCachePath("../escaped-plugin", "1.0.0", "module.js")
+	require.Error(t, err)
+
+	dst, err := s.localCachePath("my-plugin", "1.0.0", "module.js")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(dst, s.pluginCDNCacheRoot()))
+}
+
+func TestService_OpenLocalCache_RejectsTraversal(t *testing.T) {
+	s := &Service{cfg: &config.Cfg{DataPath: t.TempDir()}}
+
+	_, err := s.openLocalCache("my-plugin", "1.0.0", "../../../etc/passwd")
+	require.Error(t, err)
+}
+
+func TestService_Verify_AgainstCachedManifest(t *testing.T) {
+	s := &Service{manifests: newManifestCache(manifestCacheSize)}
+	s.manifests.add("my-plugin@1.0.0", manifest{
+		"module.js": {Algorithm: "sha384", Digest: "expected-digest"},
+	})
+
+	require.NoError(t, s.Verify(context.Background(), "my-plugin", "1.0.0", "module.js", "expected-digest"))
+
+	err := s.Verify(context.Background(), "my-plugin", "1.0.0", "module.js", "wrong-digest")
+	require.ErrorContains(t, err, "digest mismatch")
+
+	err = s.Verify(context.Background(), "my-plugin", "1.0.0", "missing.js", "anything")
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package pluginscdn
+
+import (
+	"container/list"
+	"sync"
+)
+
+// manifestCacheSize bounds how many (pluginID, pluginVersion) manifests are
+// kept in memory at once.
+const manifestCacheSize = 64
+
+// manifestCacheEntry is the value stored in a manifestCache's linked list.
+type manifestCacheEntry struct {
+	key      string
+	manifest manifest
+}
+
+// manifestCache is a small in-memory LRU cache of parsed plugin CDN
+// manifests, keyed by "<pluginID>@<pluginVersion>".
+type manifestCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+func newManifestCache(capacity int) *manifestCache {
+	return &manifestCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+func (c *manifestCache) get(key string) (manifest, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*manifestCacheEntry).manifest, true
+}
+
+func (c *manifestCache) add(key string, m manifest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*manifestCacheEntry).manifest = m
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&manifestCacheEntry{key: key, manifest: m})
+	c.items[key] = el
+
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*manifestCacheEntry).key)
+		}
+	}
+}
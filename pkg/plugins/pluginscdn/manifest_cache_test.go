@@ -0,0 +1,42 @@
+package pluginscdn
+
+import "testing"
+
+func TestManifestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newManifestCache(2)
+
+	c.add("a@1", manifest{"a.js": {Algorithm: "sha256", Digest: "a"}})
+	c.add("b@1", manifest{"b.js": {Algorithm: "sha256", Digest: "b"}})
+
+	// Touch "a" so "b" becomes the least recently used entry.
+	if _, ok := c.get("a@1"); !ok {
+		t.Fatalf("expected a@1 to be cached")
+	}
+
+	c.add("c@1", manifest{"c.js": {Algorithm: "sha256", Digest: "c"}})
+
+	if _, ok := c.get("b@1"); ok {
+		t.Fatalf("expected b@1 to have been evicted as least recently used")
+	}
+	if _, ok := c.get("a@1"); !ok {
+		t.Fatalf("expected a@1 to survive the eviction")
+	}
+	if _, ok := c.get("c@1"); !ok {
+		t.Fatalf("expected c@1 to be cached")
+	}
+}
+
+func TestManifestCache_AddOverwritesExistingKey(t *testing.T) {
+	c := newManifestCache(2)
+
+	c.add("a@1", manifest{"a.js": {Algorithm: "sha256", Digest: "old"}})
+	c.add("a@1", manifest{"a.js": {Algorithm: "sha256", Digest: "new"}})
+
+	m, ok := c.get("a@1")
+	if !ok {
+		t.Fatalf("expected a@1 to be cached")
+	}
+	if m["a.js"].Digest != "new" {
+		t.Fatalf("expected overwritten digest %q, got %q", "new", m["a.js"].Digest)
+	}
+}
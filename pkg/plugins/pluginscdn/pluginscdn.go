@@ -3,6 +3,7 @@ package pluginscdn
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 
@@ -19,10 +20,17 @@ var ErrPluginNotCDN = errors.New("plugin is not a cdn plugin")
 // Service provides methods for the plugins CDN.
 type Service struct {
 	cfg *config.Cfg
+
+	httpClient *http.Client
+	manifests  *manifestCache
 }
 
 func ProvideService(cfg *config.Cfg) *Service {
-	return &Service{cfg: cfg}
+	return &Service{
+		cfg:        cfg,
+		httpClient: http.DefaultClient,
+		manifests:  newManifestCache(manifestCacheSize),
+	}
 }
 
 // NewCDNURLConstructor returns a new URLConstructor for the provided plugin id and version.
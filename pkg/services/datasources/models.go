@@ -0,0 +1,260 @@
+package datasources
+
+import (
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/quota"
+)
+
+// Errors returned by the datasource Store and Service.
+var (
+	ErrDataSourceNotFound                = errors.New("data source not found")
+	ErrDataSourceIdentifierNotSet        = errors.New("unique identifier and org id are needed")
+	ErrDataSourceNameExists              = errors.New("data source with the same name already exists")
+	ErrDataSourceUidExists               = errors.New("data source with the same uid already exists")
+	ErrDataSourceUpdatingOldVersion      = errors.New("trying to update old version of datasource")
+	ErrDataSourceFailedGenerateUniqueUid = errors.New("failed to generate unique datasource ID")
+	ErrDataSourceSoftDeletedConflict     = errors.New("a soft-deleted data source with the same name or uid exists, restore it instead")
+)
+
+const (
+	QuotaTargetSrv quota.TargetSrv = "data_source"
+	QuotaTarget    quota.Target    = "data_source"
+)
+
+// ScopeProvider is used to construct accesscontrol scopes for datasources.
+var ScopeProvider = ac.NewScopeProvider("datasources")
+
+// DataSource is the model for the data_source table.
+type DataSource struct {
+	ID      int64 `xorm:"pk autoincr 'id'"`
+	OrgID   int64 `xorm:"org_id"`
+	Version int
+
+	Name            string
+	Type            string
+	Access          DsAccess
+	URL             string
+	User            string
+	Database        string
+	BasicAuth       bool
+	BasicAuthUser   string
+	WithCredentials bool
+	IsDefault       bool
+	JsonData        *simplejson.Json
+	SecureJsonData  map[string][]byte
+	ReadOnly        bool
+	UID             string `xorm:"uid"`
+
+	Created time.Time
+	Updated time.Time
+
+	// DeletedAt is set when the datasource has been soft-deleted. A nil
+	// value means the datasource is active. Rows with DeletedAt set are
+	// excluded from all Get* queries unless IncludeDeleted is requested.
+	DeletedAt *time.Time
+}
+
+// DsAccess is the datasource access mode (proxy or direct).
+type DsAccess string
+
+// GetDataSourceQuery looks up a single datasource by org_id and one of
+// id, uid (preferred) or name.
+type GetDataSourceQuery struct {
+	ID    int64
+	UID   string
+	Name  string
+	OrgID int64
+
+	// IncludeDeleted, when true, allows the query to return a soft-deleted
+	// datasource instead of ErrDataSourceNotFound.
+	IncludeDeleted bool
+
+	Result *DataSource
+}
+
+// GetDataSourcesQuery fetches every datasource belonging to an org.
+type GetDataSourcesQuery struct {
+	OrgID           int64
+	DataSourceLimit int
+
+	// AfterUID, together with PageSize, enables keyset/cursor pagination:
+	// results start right after the position it names in the (name, uid)
+	// ordering. It takes either a NextCursor from a previous page or a bare
+	// datasource UID. Leave empty to start from the first page.
+	AfterUID string
+	// PageSize caps the number of rows returned. When zero, DataSourceLimit
+	// (or no limit) applies instead and NextCursor is left empty.
+	PageSize int
+	Filter   DataSourceFilter
+
+	Result []*DataSource
+	// NextCursor is the AfterUID to pass to fetch the following page, or
+	// empty when there are no more results.
+	NextCursor string
+}
+
+// DataSourceFilter narrows down a datasource listing query.
+type DataSourceFilter struct {
+	NameContains   string
+	TypeIn         []string
+	IsDefault      *bool
+	UpdatedSince   time.Time
+	IncludeDeleted bool
+}
+
+// GetDataSourcesByTypeQuery fetches datasources of a given type, optionally
+// scoped to a single org.
+type GetDataSourcesByTypeQuery struct {
+	Type  string
+	OrgID int64
+
+	Result []*DataSource
+}
+
+// GetDefaultDataSourceQuery fetches the default datasource of an org.
+type GetDefaultDataSourceQuery struct {
+	OrgID int64
+
+	Result *DataSource
+}
+
+// GetAllDataSourcesQuery fetches every datasource across all orgs.
+type GetAllDataSourcesQuery struct {
+	Filter DataSourceFilter
+
+	Result []*DataSource
+}
+
+// DeleteDataSourceCommand deletes a datasource by org_id and one of id, uid
+// (preferred) or name.
+type DeleteDataSourceCommand struct {
+	ID    int64
+	UID   string
+	Name  string
+	OrgID int64
+
+	// SoftDelete, when true, marks the datasource as deleted instead of
+	// removing its row. Permission cleanup is deferred until the
+	// datasource is purged.
+	SoftDelete bool
+
+	UpdateSecretFn          UpdateSecretFn
+	DeletedDatasourcesCount int64
+}
+
+// RestoreDataSourceCommand resurrects a soft-deleted datasource, restoring
+// its original UID, JsonData, SecureJsonData and ACL permissions.
+type RestoreDataSourceCommand struct {
+	ID    int64
+	UID   string
+	OrgID int64
+
+	Result *DataSource
+}
+
+// ListDeletedQuery lists the soft-deleted datasources of an org awaiting
+// purge or restore.
+type ListDeletedQuery struct {
+	OrgID int64
+
+	Result []*DataSource
+}
+
+// AddDataSourceCommand creates a new datasource.
+type AddDataSourceCommand struct {
+	Name            string
+	Type            string
+	Access          DsAccess
+	URL             string
+	User            string
+	Database        string
+	BasicAuth       bool
+	BasicAuthUser   string
+	WithCredentials bool
+	IsDefault       bool
+	JsonData        *simplejson.Json
+
+	EncryptedSecureJsonData map[string][]byte
+	ReadOnly                bool
+	UID                     string
+	OrgID                   int64
+
+	UpdateSecretFn UpdateSecretFn
+	Result         *DataSource
+}
+
+// UpdateDataSourceCommand updates an existing datasource.
+type UpdateDataSourceCommand struct {
+	ID              int64
+	Name            string
+	Type            string
+	Access          DsAccess
+	URL             string
+	User            string
+	Database        string
+	BasicAuth       bool
+	BasicAuthUser   string
+	WithCredentials bool
+	IsDefault       bool
+	JsonData        *simplejson.Json
+
+	EncryptedSecureJsonData map[string][]byte
+	ReadOnly                bool
+	Version                 int
+	UID                     string
+	OrgID                   int64
+
+	UpdateSecretFn UpdateSecretFn
+	Result         *DataSource
+}
+
+// UpdateSecretFn is called inside the storage transaction once the
+// datasource row has been written, so that secret updates roll back
+// together with the row on failure.
+type UpdateSecretFn func() error
+
+// DeleteSecretsFn is called once per datasource as it is purged, so that
+// secrets left in place for a soft-deleted row are cleaned up alongside its
+// permissions.
+type DeleteSecretsFn func(ds *DataSource) error
+
+// ApplyOptions controls how ApplyDataSources reconciles the desired set of
+// datasources against the current state.
+type ApplyOptions struct {
+	// Prune, when true, deletes existing datasources that are absent from
+	// the desired set. When false, those datasources are left untouched.
+	Prune bool
+	// ContinueOnError, when true, keeps reconciling the remaining
+	// datasources after a per-item error instead of aborting the whole
+	// apply. The failing items are reported in ApplyResult with their
+	// Error set; the transaction still commits the items that succeeded.
+	ContinueOnError bool
+}
+
+// ApplyAction describes what ApplyDataSources did with a single datasource.
+type ApplyAction string
+
+const (
+	ApplyActionCreated   ApplyAction = "created"
+	ApplyActionUpdated   ApplyAction = "updated"
+	ApplyActionUnchanged ApplyAction = "unchanged"
+	ApplyActionDeleted   ApplyAction = "deleted"
+)
+
+// ApplyDataSourceResult reports the outcome of reconciling a single
+// datasource as part of ApplyDataSources.
+type ApplyDataSourceResult struct {
+	UID    string
+	Name   string
+	Action ApplyAction
+	Error  error
+}
+
+// ApplyResult is the outcome of a call to ApplyDataSources.
+type ApplyResult struct {
+	Results []ApplyDataSourceResult
+}
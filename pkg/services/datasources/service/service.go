@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// DefaultSoftDeleteRetention is used when datasources.soft_delete_retention
+// is left unset.
+const DefaultSoftDeleteRetention = 24 * time.Hour
+
+// SecretsService deletes the secrets belonging to a single datasource. It is
+// satisfied by the secrets service's kvstore-backed implementation, narrowed
+// down to the one method the purge loop needs.
+type SecretsService interface {
+	DeleteSecrets(ctx context.Context, ds *datasources.DataSource) error
+}
+
+// Service runs the datasource Store's background housekeeping: purging
+// soft-deleted datasources once they've outlived their retention window.
+type Service struct {
+	store     *SqlStore
+	retention time.Duration
+	secrets   SecretsService
+	logger    log.Logger
+}
+
+// ProvideService wires Service from the global Grafana configuration,
+// reading the purge retention from datasources.soft_delete_retention.
+func ProvideService(store *SqlStore, cfg *setting.Cfg, secrets SecretsService) *Service {
+	retention := cfg.DataSourceSoftDeleteRetention
+	if retention <= 0 {
+		retention = DefaultSoftDeleteRetention
+	}
+
+	return &Service{
+		store:     store,
+		retention: retention,
+		secrets:   secrets,
+		logger:    log.New("datasources"),
+	}
+}
+
+// Run implements registry.BackgroundService: it blocks until ctx is
+// cancelled, periodically purging soft-deleted datasources past retention.
+func (s *Service) Run(ctx context.Context) error {
+	deleteSecrets := func(ds *datasources.DataSource) error {
+		return s.secrets.DeleteSecrets(ctx, ds)
+	}
+	return s.store.RunDeletedDataSourcePurge(ctx, s.retention, deleteSecrets)
+}
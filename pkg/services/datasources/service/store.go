@@ -1,7 +1,9 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"strings"
@@ -31,6 +33,9 @@ type Store interface {
 	AddDataSource(context.Context, *datasources.AddDataSourceCommand) error
 	UpdateDataSource(context.Context, *datasources.UpdateDataSourceCommand) error
 	GetAllDataSources(ctx context.Context, query *datasources.GetAllDataSourcesQuery) error
+	ApplyDataSources(ctx context.Context, orgID int64, desired []datasources.DataSource, opts datasources.ApplyOptions) (datasources.ApplyResult, error)
+	RestoreDataSource(context.Context, *datasources.RestoreDataSourceCommand) error
+	ListDeletedDataSources(context.Context, *datasources.ListDeletedQuery) error
 
 	Count(context.Context, *quota.ScopeParameters) (*quota.Map, error)
 }
@@ -60,7 +65,11 @@ func (ss *SqlStore) getDataSource(ctx context.Context, query *datasources.GetDat
 	}
 
 	datasource := &datasources.DataSource{Name: query.Name, OrgID: query.OrgID, ID: query.ID, UID: query.UID}
-	has, err := sess.Get(datasource)
+	q := sess.Where("org_id=?", query.OrgID)
+	if !query.IncludeDeleted {
+		q = q.Where("deleted_at IS NULL")
+	}
+	has, err := q.Get(datasource)
 
 	if err != nil {
 		ss.logger.Error("Failed getting data source", "err", err, "uid", query.UID, "id", query.ID, "name", query.Name, "orgId", query.OrgID)
@@ -74,27 +83,139 @@ func (ss *SqlStore) getDataSource(ctx context.Context, query *datasources.GetDat
 	return nil
 }
 
+// GetDataSources returns the datasources of an org. When query.PageSize is
+// set, it keyset-paginates by (name, uid) starting right after
+// query.AfterUID, and leaves a NextCursor in the result for the caller to
+// pass back as AfterUID to fetch the following page; an empty NextCursor
+// means there are no more rows. NextCursor encodes the (name, uid) seek
+// position itself, so a later page doesn't depend on the referenced row
+// still existing -- it may have been deleted or pruned since. Without a
+// PageSize, the legacy DataSourceLimit offset behaviour is preserved for
+// existing callers.
 func (ss *SqlStore) GetDataSources(ctx context.Context, query *datasources.GetDataSourcesQuery) error {
-	var sess *xorm.Session
 	return ss.db.WithDbSession(ctx, func(dbSess *db.Session) error {
-		if query.DataSourceLimit <= 0 {
-			sess = dbSess.Where("org_id=?", query.OrgID).Asc("name")
+		sess := dbSess.Where("org_id=?", query.OrgID)
+		if !query.Filter.IncludeDeleted {
+			sess = sess.Where("deleted_at IS NULL")
+		}
+		sess = applyDataSourceFilter(sess, query.Filter)
+
+		query.NextCursor = ""
+		if query.PageSize > 0 {
+			if query.AfterUID != "" {
+				afterName, afterUID, err := ss.seekPosition(ctx, query.OrgID, query.AfterUID)
+				if err != nil {
+					return err
+				}
+				sess = sess.Where("(name > ?) OR (name = ? AND uid > ?)", afterName, afterName, afterUID)
+			}
+			// Fetch one extra row to know whether a further page exists,
+			// without a second round-trip.
+			sess = sess.Asc("name").Asc("uid").Limit(query.PageSize+1, 0)
+		} else if query.DataSourceLimit > 0 {
+			sess = sess.Limit(query.DataSourceLimit, 0).Asc("name")
 		} else {
-			sess = dbSess.Limit(query.DataSourceLimit, 0).Where("org_id=?", query.OrgID).Asc("name")
+			sess = sess.Asc("name")
 		}
 
 		query.Result = make([]*datasources.DataSource, 0)
-		return sess.Find(&query.Result)
+		if err := sess.Find(&query.Result); err != nil {
+			return err
+		}
+
+		if query.PageSize > 0 && len(query.Result) > query.PageSize {
+			last := query.Result[query.PageSize-1]
+			query.NextCursor = encodeCursor(last.Name, last.UID)
+			query.Result = query.Result[:query.PageSize]
+		}
+
+		return nil
 	})
 }
 
+// seekPosition resolves an AfterUID into the (name, uid) position to seek
+// from. AfterUID is usually a cursor produced by encodeCursor, decoded
+// without touching the database; a bare UID (e.g. a caller starting
+// pagination from a known datasource rather than a NextCursor) falls back
+// to a lookup of that row, done on its own session so it can't clobber the
+// statement GetDataSources is still building on the caller's session.
+func (ss *SqlStore) seekPosition(ctx context.Context, orgID int64, afterUID string) (name, uid string, err error) {
+	if name, uid, ok := decodeCursor(afterUID); ok {
+		return name, uid, nil
+	}
+
+	err = ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		ds := &datasources.DataSource{}
+		has, err := sess.Where("org_id=? AND uid=?", orgID, afterUID).Get(ds)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return datasources.ErrDataSourceNotFound
+		}
+		name, uid = ds.Name, ds.UID
+		return nil
+	})
+	return name, uid, err
+}
+
+// cursorSeparator can't appear in a datasource name or UID, so it safely
+// joins the two fields encodeCursor packs into a NextCursor.
+const cursorSeparator = "\x00"
+
+// encodeCursor packs the (name, uid) seek position into the opaque cursor
+// string returned as NextCursor, so a later page can decode it without
+// re-querying the row it points past.
+func encodeCursor(name, uid string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name + cursorSeparator + uid))
+}
+
+// decodeCursor unpacks a cursor produced by encodeCursor. ok is false if
+// cursor isn't in that format, e.g. because a caller passed a bare UID.
+func decodeCursor(cursor string) (name, uid string, ok bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.SplitN(string(raw), cursorSeparator, 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// GetAllDataSources returns datasources across every org, so admin tooling
+// can enumerate and filter them without loading org-by-org.
 func (ss *SqlStore) GetAllDataSources(ctx context.Context, query *datasources.GetAllDataSourcesQuery) error {
 	return ss.db.WithDbSession(ctx, func(sess *db.Session) error {
 		query.Result = make([]*datasources.DataSource, 0)
-		return sess.Asc("name").Find(&query.Result)
+		s := sess.Asc("name")
+		if !query.Filter.IncludeDeleted {
+			s = s.Where("deleted_at IS NULL")
+		}
+		s = applyDataSourceFilter(s, query.Filter)
+		return s.Find(&query.Result)
 	})
 }
 
+// applyDataSourceFilter adds the optional NameContains, TypeIn, IsDefault and
+// UpdatedSince conditions from filter to sess.
+func applyDataSourceFilter(sess *xorm.Session, filter datasources.DataSourceFilter) *xorm.Session {
+	if filter.NameContains != "" {
+		sess = sess.Where("name LIKE ?", "%"+filter.NameContains+"%")
+	}
+	if len(filter.TypeIn) > 0 {
+		sess = sess.In("type", filter.TypeIn)
+	}
+	if filter.IsDefault != nil {
+		sess = sess.Where("is_default=?", *filter.IsDefault)
+	}
+	if !filter.UpdatedSince.IsZero() {
+		sess = sess.Where("updated >= ?", filter.UpdatedSince)
+	}
+	return sess
+}
+
 // GetDataSourcesByType returns all datasources for a given type or an error if the specified type is an empty string
 func (ss *SqlStore) GetDataSourcesByType(ctx context.Context, query *datasources.GetDataSourcesByTypeQuery) error {
 	if query.Type == "" {
@@ -104,9 +225,9 @@ func (ss *SqlStore) GetDataSourcesByType(ctx context.Context, query *datasources
 	query.Result = make([]*datasources.DataSource, 0)
 	return ss.db.WithDbSession(ctx, func(sess *db.Session) error {
 		if query.OrgID > 0 {
-			return sess.Where("type=? AND org_id=?", query.Type, query.OrgID).Asc("id").Find(&query.Result)
+			return sess.Where("type=? AND org_id=? AND deleted_at IS NULL", query.Type, query.OrgID).Asc("id").Find(&query.Result)
 		}
-		return sess.Where("type=?", query.Type).Asc("id").Find(&query.Result)
+		return sess.Where("type=? AND deleted_at IS NULL", query.Type).Asc("id").Find(&query.Result)
 	})
 }
 
@@ -114,7 +235,7 @@ func (ss *SqlStore) GetDataSourcesByType(ctx context.Context, query *datasources
 func (ss *SqlStore) GetDefaultDataSource(ctx context.Context, query *datasources.GetDefaultDataSourceQuery) error {
 	datasource := datasources.DataSource{}
 	return ss.db.WithDbSession(ctx, func(sess *db.Session) error {
-		exists, err := sess.Where("org_id=? AND is_default=?", query.OrgID, true).Get(&datasource)
+		exists, err := sess.Where("org_id=? AND is_default=? AND deleted_at IS NULL", query.OrgID, true).Get(&datasource)
 
 		if !exists {
 			return datasources.ErrDataSourceNotFound
@@ -127,6 +248,12 @@ func (ss *SqlStore) GetDefaultDataSource(ctx context.Context, query *datasources
 
 // DeleteDataSource removes a datasource by org_id as well as either uid (preferred), id, or name
 // and is added to the bus. It also removes permissions related to the datasource.
+//
+// If cmd.SoftDelete is set, the row is kept and merely marked as deleted_at
+// instead: it stops showing up in Get* queries but can still be brought back
+// with RestoreDataSource. Its permissions are left alone until the
+// datasource is purged, so a restore within the retention window gets its
+// ACL back intact.
 func (ss *SqlStore) DeleteDataSource(ctx context.Context, cmd *datasources.DeleteDataSourceCommand) error {
 	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
 		dsQuery := &datasources.GetDataSourceQuery{ID: cmd.ID, UID: cmd.UID, Name: cmd.Name, OrgID: cmd.OrgID}
@@ -138,30 +265,43 @@ func (ss *SqlStore) DeleteDataSource(ctx context.Context, cmd *datasources.Delet
 
 		ds := dsQuery.Result
 		if ds != nil {
-			// Delete the data source
-			result, err := sess.Exec("DELETE FROM data_source WHERE org_id=? AND id=?", ds.OrgID, ds.ID)
-			if err != nil {
-				return err
-			}
-
-			cmd.DeletedDatasourcesCount, _ = result.RowsAffected()
-
-			// Remove associated AccessControl permissions
-			if _, errDeletingPerms := sess.Exec("DELETE FROM permission WHERE scope=?",
-				ac.Scope(datasources.ScopeProvider.GetResourceScope(dsQuery.Result.UID))); errDeletingPerms != nil {
-				return errDeletingPerms
+			if cmd.SoftDelete {
+				result, err := sess.Exec("UPDATE data_source SET deleted_at=? WHERE org_id=? AND id=? AND deleted_at IS NULL", time.Now(), ds.OrgID, ds.ID)
+				if err != nil {
+					return err
+				}
+				cmd.DeletedDatasourcesCount, _ = result.RowsAffected()
+			} else {
+				// Delete the data source
+				result, err := sess.Exec("DELETE FROM data_source WHERE org_id=? AND id=?", ds.OrgID, ds.ID)
+				if err != nil {
+					return err
+				}
+
+				cmd.DeletedDatasourcesCount, _ = result.RowsAffected()
+
+				// Remove associated AccessControl permissions
+				if _, errDeletingPerms := sess.Exec("DELETE FROM permission WHERE scope=?",
+					ac.Scope(datasources.ScopeProvider.GetResourceScope(dsQuery.Result.UID))); errDeletingPerms != nil {
+					return errDeletingPerms
+				}
 			}
 		}
 
-		if cmd.UpdateSecretFn != nil {
+		// A soft delete keeps the row around for a possible restore, so its
+		// secrets must stay put too; cmd.UpdateSecretFn is not called for it.
+		// Its secrets are cleaned up at purge time instead, through the
+		// DeleteSecretsFn passed to purgeDeletedDataSources.
+		if cmd.UpdateSecretFn != nil && !cmd.SoftDelete {
 			if err := cmd.UpdateSecretFn(); err != nil {
 				ss.logger.Error("Failed to update datasource secrets -- rolling back update", "UID", cmd.UID, "name", cmd.Name, "orgId", cmd.OrgID)
 				return err
 			}
 		}
 
-		// Publish data source deletion event
-		if cmd.DeletedDatasourcesCount > 0 {
+		// Publish data source deletion event. A soft delete is recoverable,
+		// so it is not announced as a deletion until it is actually purged.
+		if cmd.DeletedDatasourcesCount > 0 && !cmd.SoftDelete {
 			sess.PublishAfterCommit(&events.DataSourceDeleted{
 				Timestamp: time.Now(),
 				Name:      ds.Name,
@@ -219,15 +359,41 @@ func (ss *SqlStore) Count(ctx context.Context, scopeParams *quota.ScopeParameter
 	return u, nil
 }
 
+// softDeletedConflict reports whether a soft-deleted datasource already
+// occupies the given UID or (org_id, name) slot, e.g. when AddDataSource or
+// ApplyDataSources wants to (re)create a row that a prior soft-delete left
+// in place for its retention window.
+func (ss *SqlStore) softDeletedConflict(sess *db.Session, orgID int64, uid, name string) (bool, error) {
+	byName := datasources.DataSource{OrgID: orgID, Name: name}
+	has, err := sess.Where("deleted_at IS NOT NULL").Get(&byName)
+	if err != nil || has {
+		return has, err
+	}
+
+	if uid == "" {
+		return false, nil
+	}
+	byUID := datasources.DataSource{OrgID: orgID, UID: uid}
+	return sess.Where("deleted_at IS NOT NULL").Get(&byUID)
+}
+
 func (ss *SqlStore) AddDataSource(ctx context.Context, cmd *datasources.AddDataSourceCommand) error {
 	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
 		existing := datasources.DataSource{OrgID: cmd.OrgID, Name: cmd.Name}
-		has, _ := sess.Get(&existing)
+		has, _ := sess.Where("deleted_at IS NULL").Get(&existing)
 
 		if has {
 			return datasources.ErrDataSourceNameExists
 		}
 
+		conflict, err := ss.softDeletedConflict(sess, cmd.OrgID, cmd.UID, cmd.Name)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return datasources.ErrDataSourceSoftDeletedConflict
+		}
+
 		if cmd.JsonData == nil {
 			cmd.JsonData = simplejson.New()
 		}
@@ -377,6 +543,275 @@ func (ss *SqlStore) UpdateDataSource(ctx context.Context, cmd *datasources.Updat
 	})
 }
 
+// ApplyDataSources reconciles the desired set of datasources for an org
+// against the current state in a single transaction: it inserts datasources
+// that don't exist yet, updates the ones that changed (matched by UID), and,
+// when opts.Prune is set, deletes the ones that are no longer desired.
+// Unless opts.ContinueOnError is set, the first per-item error aborts and
+// rolls back the whole apply; otherwise each item runs inside its own SQL
+// SAVEPOINT, so a failure only rolls back that one item instead of poisoning
+// the transaction for the rest (notably on Postgres, where any statement
+// error blocks further statements until a rollback). The item's error is
+// recorded in the returned ApplyResult and reconciliation continues with the
+// rest. Creation and deletion events are published atomically once the
+// transaction commits.
+func (ss *SqlStore) ApplyDataSources(ctx context.Context, orgID int64, desired []datasources.DataSource, opts datasources.ApplyOptions) (datasources.ApplyResult, error) {
+	result := datasources.ApplyResult{Results: make([]datasources.ApplyDataSourceResult, 0, len(desired))}
+
+	var created []*datasources.DataSource
+	var deleted []*datasources.DataSource
+
+	err := ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		// Soft-deleted rows are not "current": they must stay invisible to
+		// reconciliation so a Prune doesn't hard-delete something still in
+		// its retention window, and so a desired item reusing their UID
+		// goes through the same soft-deleted-conflict check as AddDataSource
+		// instead of silently colliding with it.
+		current := make([]*datasources.DataSource, 0)
+		if err := sess.Where("org_id=? AND deleted_at IS NULL", orgID).Find(&current); err != nil {
+			return err
+		}
+
+		currentByUID := make(map[string]*datasources.DataSource, len(current))
+		for _, ds := range current {
+			currentByUID[ds.UID] = ds
+		}
+		desiredUIDs := make(map[string]struct{}, len(desired))
+
+		savepoint := 0
+		for i := range desired {
+			ds := desired[i]
+			itemResult := datasources.ApplyDataSourceResult{UID: ds.UID, Name: ds.Name}
+
+			var applyErr error
+			if opts.ContinueOnError {
+				savepoint++
+				applyErr = ss.withSavepoint(sess, fmt.Sprintf("apply_ds_%d", savepoint), func() error {
+					return ss.applyOneDataSource(sess, orgID, &ds, currentByUID[ds.UID], &itemResult, &created)
+				})
+			} else {
+				applyErr = ss.applyOneDataSource(sess, orgID, &ds, currentByUID[ds.UID], &itemResult, &created)
+			}
+			desiredUIDs[ds.UID] = struct{}{}
+
+			if applyErr != nil {
+				itemResult.Error = applyErr
+				if !opts.ContinueOnError {
+					return applyErr
+				}
+			}
+			result.Results = append(result.Results, itemResult)
+		}
+
+		if opts.Prune {
+			for uid, ds := range currentByUID {
+				if _, wanted := desiredUIDs[uid]; wanted {
+					continue
+				}
+
+				itemResult := datasources.ApplyDataSourceResult{UID: ds.UID, Name: ds.Name}
+
+				var pruneErr error
+				if opts.ContinueOnError {
+					savepoint++
+					pruneErr = ss.withSavepoint(sess, fmt.Sprintf("apply_ds_%d", savepoint), func() error {
+						return ss.pruneOneDataSource(sess, orgID, ds)
+					})
+				} else {
+					pruneErr = ss.pruneOneDataSource(sess, orgID, ds)
+				}
+
+				if pruneErr != nil {
+					itemResult.Error = pruneErr
+					if !opts.ContinueOnError {
+						return pruneErr
+					}
+				} else {
+					itemResult.Action = datasources.ApplyActionDeleted
+					deleted = append(deleted, ds)
+				}
+				result.Results = append(result.Results, itemResult)
+			}
+		}
+
+		for _, ds := range created {
+			sess.PublishAfterCommit(&events.DataSourceCreated{
+				Timestamp: time.Now(),
+				Name:      ds.Name,
+				ID:        ds.ID,
+				UID:       ds.UID,
+				OrgID:     ds.OrgID,
+			})
+		}
+		for _, ds := range deleted {
+			sess.PublishAfterCommit(&events.DataSourceDeleted{
+				Timestamp: time.Now(),
+				Name:      ds.Name,
+				ID:        ds.ID,
+				UID:       ds.UID,
+				OrgID:     ds.OrgID,
+			})
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// applyOneDataSource inserts ds if existing is nil, updates it in place if
+// existing differs from ds, or leaves itemResult as unchanged otherwise. On
+// insert, *created is appended to so the caller can publish the event once
+// the transaction commits.
+func (ss *SqlStore) applyOneDataSource(sess *db.Session, orgID int64, ds *datasources.DataSource, existing *datasources.DataSource, itemResult *datasources.ApplyDataSourceResult, created *[]*datasources.DataSource) error {
+	if ds.JsonData == nil {
+		ds.JsonData = simplejson.New()
+	}
+
+	if existing == nil {
+		conflict, err := ss.softDeletedConflict(sess, orgID, ds.UID, ds.Name)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return datasources.ErrDataSourceSoftDeletedConflict
+		}
+
+		if ds.UID == "" {
+			uid, err := generateNewDatasourceUid(sess, orgID)
+			if err != nil {
+				return fmt.Errorf("failed to generate UID for datasource %q: %w", ds.Name, err)
+			}
+			ds.UID = uid
+			itemResult.UID = uid
+		}
+		ds.OrgID = orgID
+		ds.Created = time.Now()
+		ds.Updated = time.Now()
+		ds.Version = 1
+
+		if _, err := sess.Insert(ds); err != nil {
+			if ss.db.GetDialect().IsUniqueConstraintViolation(err) && strings.Contains(strings.ToLower(ss.db.GetDialect().ErrorMessage(err)), "uid") {
+				return datasources.ErrDataSourceUidExists
+			}
+			return err
+		}
+		if err := updateIsDefaultFlag(ds, sess); err != nil {
+			return err
+		}
+
+		*created = append(*created, ds)
+		itemResult.Action = datasources.ApplyActionCreated
+		return nil
+	}
+
+	if dataSourcesEqual(existing, ds) {
+		itemResult.Action = datasources.ApplyActionUnchanged
+		return nil
+	}
+
+	ds.ID = existing.ID
+	ds.OrgID = orgID
+	ds.Created = existing.Created
+	ds.Updated = time.Now()
+	ds.Version = existing.Version + 1
+
+	sess.UseBool("is_default")
+	sess.UseBool("basic_auth")
+	sess.UseBool("with_credentials")
+	sess.UseBool("read_only")
+	sess.MustCols("database")
+	sess.MustCols("password")
+	sess.MustCols("basic_auth_password")
+	sess.MustCols("user")
+	sess.MustCols("secure_json_data")
+
+	if _, err := sess.Where("id=? and org_id=?", ds.ID, orgID).Update(ds); err != nil {
+		return err
+	}
+	if err := updateIsDefaultFlag(ds, sess); err != nil {
+		return err
+	}
+
+	itemResult.Action = datasources.ApplyActionUpdated
+	return nil
+}
+
+// pruneOneDataSource deletes a datasource no longer present in the desired
+// set, along with its access control permissions.
+func (ss *SqlStore) pruneOneDataSource(sess *db.Session, orgID int64, ds *datasources.DataSource) error {
+	if _, err := sess.Exec("DELETE FROM data_source WHERE org_id=? AND id=?", orgID, ds.ID); err != nil {
+		return err
+	}
+	if _, err := sess.Exec("DELETE FROM permission WHERE scope=?",
+		ac.Scope(datasources.ScopeProvider.GetResourceScope(ds.UID))); err != nil {
+		return err
+	}
+	return nil
+}
+
+// withSavepoint runs fn inside its own SQL SAVEPOINT and rolls back just
+// that savepoint -- not the whole transaction -- if fn fails, so callers
+// like ApplyDataSources can honor opts.ContinueOnError without one item's
+// failure leaving the transaction unable to accept further statements.
+func (ss *SqlStore) withSavepoint(sess *db.Session, name string, fn func() error) error {
+	if _, err := sess.Exec(fmt.Sprintf("SAVEPOINT %s", name)); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rollbackErr := sess.Exec(fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", name)); rollbackErr != nil {
+			return rollbackErr
+		}
+		return err
+	}
+
+	_, err := sess.Exec(fmt.Sprintf("RELEASE SAVEPOINT %s", name))
+	return err
+}
+
+// dataSourcesEqual reports whether applying desired over current would be a
+// no-op, i.e. whether every field ApplyDataSources can change is already
+// equal.
+func dataSourcesEqual(current, desired *datasources.DataSource) bool {
+	if current.Name != desired.Name || current.Type != desired.Type || current.Access != desired.Access ||
+		current.URL != desired.URL || current.User != desired.User || current.Database != desired.Database ||
+		current.BasicAuth != desired.BasicAuth || current.BasicAuthUser != desired.BasicAuthUser ||
+		current.WithCredentials != desired.WithCredentials || current.IsDefault != desired.IsDefault ||
+		current.ReadOnly != desired.ReadOnly {
+		return false
+	}
+
+	var currentJSON, desiredJSON []byte
+	if current.JsonData != nil {
+		currentJSON, _ = current.JsonData.MarshalJSON()
+	}
+	if desired.JsonData != nil {
+		desiredJSON, _ = desired.JsonData.MarshalJSON()
+	}
+	if string(currentJSON) != string(desiredJSON) {
+		return false
+	}
+
+	return secureJSONDataEqual(current.SecureJsonData, desired.SecureJsonData)
+}
+
+// secureJSONDataEqual reports whether a and b hold the same keys and byte
+// values, so a desired item whose secrets changed isn't reported Unchanged
+// and silently left un-updated.
+func secureJSONDataEqual(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, av := range a {
+		bv, ok := b[k]
+		if !ok || !bytes.Equal(av, bv) {
+			return false
+		}
+	}
+	return true
+}
+
 func generateNewDatasourceUid(sess *db.Session, orgId int64) (string, error) {
 	for i := 0; i < 3; i++ {
 		uid := generateNewUid()
@@ -395,3 +830,104 @@ func generateNewDatasourceUid(sess *db.Session, orgId int64) (string, error) {
 }
 
 var generateNewUid func() string = util.GenerateShortUID
+
+// RestoreDataSource resurrects a soft-deleted datasource, clearing its
+// deleted_at so it shows up in Get* queries again with its original UID,
+// JsonData, SecureJsonData and ACL permissions intact -- none of those are
+// touched by a soft delete, only deleted_at is set.
+func (ss *SqlStore) RestoreDataSource(ctx context.Context, cmd *datasources.RestoreDataSourceCommand) error {
+	if cmd.OrgID == 0 || (cmd.ID == 0 && cmd.UID == "") {
+		return datasources.ErrDataSourceIdentifierNotSet
+	}
+
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		ds := &datasources.DataSource{OrgID: cmd.OrgID, ID: cmd.ID, UID: cmd.UID}
+		has, err := sess.Where("deleted_at IS NOT NULL").Get(ds)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return datasources.ErrDataSourceNotFound
+		}
+
+		if _, err := sess.Exec("UPDATE data_source SET deleted_at=NULL WHERE org_id=? AND id=?", ds.OrgID, ds.ID); err != nil {
+			return err
+		}
+
+		ds.DeletedAt = nil
+		cmd.Result = ds
+		return nil
+	})
+}
+
+// ListDeletedDataSources lists the soft-deleted datasources of an org that
+// have not been purged yet, awaiting restore or purge. Rows past their
+// retention window are included until the next purge removes them.
+func (ss *SqlStore) ListDeletedDataSources(ctx context.Context, query *datasources.ListDeletedQuery) error {
+	return ss.db.WithDbSession(ctx, func(sess *db.Session) error {
+		query.Result = make([]*datasources.DataSource, 0)
+		return sess.Where("org_id=? AND deleted_at IS NOT NULL", query.OrgID).Asc("name").Find(&query.Result)
+	})
+}
+
+// purgeCheckInterval is how often RunDeletedDataSourcePurge wakes up to look
+// for soft-deleted datasources that have outlived their retention window.
+const purgeCheckInterval = time.Hour
+
+// RunDeletedDataSourcePurge blocks until ctx is cancelled, periodically
+// hard-deleting datasources that have been soft-deleted for longer than
+// retention, along with their permissions. It is meant to be started once by
+// the Service as a background goroutine, with retention sourced from the
+// datasources.soft_delete_retention setting.
+func (ss *SqlStore) RunDeletedDataSourcePurge(ctx context.Context, retention time.Duration, deleteSecretsFn datasources.DeleteSecretsFn) error {
+	ticker := time.NewTicker(purgeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ss.purgeDeletedDataSources(ctx, retention, deleteSecretsFn); err != nil {
+				ss.logger.Error("Failed to purge soft-deleted data sources", "err", err)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// purgeDeletedDataSources hard-deletes every datasource whose deleted_at is
+// older than retention, along with the permissions that were left in place
+// for a possible restore. deleteSecretsFn, when set, is called for each
+// purged datasource before its row is removed, so secrets deferred at
+// soft-delete time are cleaned up here instead. A DataSourceDeleted event is
+// published for each purged datasource once the transaction commits.
+func (ss *SqlStore) purgeDeletedDataSources(ctx context.Context, retention time.Duration, deleteSecretsFn datasources.DeleteSecretsFn) error {
+	cutoff := time.Now().Add(-retention)
+
+	return ss.db.WithTransactionalDbSession(ctx, func(sess *db.Session) error {
+		toPurge := make([]*datasources.DataSource, 0)
+		if err := sess.Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&toPurge); err != nil {
+			return err
+		}
+
+		for _, ds := range toPurge {
+			if deleteSecretsFn != nil {
+				if err := deleteSecretsFn(ds); err != nil {
+					return err
+				}
+			}
+			if err := ss.pruneOneDataSource(sess, ds.OrgID, ds); err != nil {
+				return err
+			}
+			sess.PublishAfterCommit(&events.DataSourceDeleted{
+				Timestamp: time.Now(),
+				Name:      ds.Name,
+				ID:        ds.ID,
+				UID:       ds.UID,
+				OrgID:     ds.OrgID,
+			})
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/db"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func createTestStore(t *testing.T) *SqlStore {
+	t.Helper()
+	return CreateStore(sqlstore.InitTestDB(t), log.New("test"))
+}
+
+func TestApplyDataSources_CreatesUpdatesAndPrunes(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	result, err := store.ApplyDataSources(ctx, orgID, []datasources.DataSource{
+		{Name: "keep-me", Type: "prometheus", UID: "keep"},
+		{Name: "drop-me", Type: "loki", UID: "drop"},
+	}, datasources.ApplyOptions{})
+	require.NoError(t, err)
+	require.Len(t, result.Results, 2)
+	for _, r := range result.Results {
+		require.Equal(t, datasources.ApplyActionCreated, r.Action)
+		require.NoError(t, r.Error)
+	}
+
+	// Re-applying the same desired set is a no-op, and pruning the
+	// now-undesired "drop-me" datasource removes it.
+	result, err = store.ApplyDataSources(ctx, orgID, []datasources.DataSource{
+		{Name: "keep-me", Type: "prometheus", UID: "keep"},
+	}, datasources.ApplyOptions{Prune: true})
+	require.NoError(t, err)
+	require.Len(t, result.Results, 2)
+
+	var sawUnchanged, sawDeleted bool
+	for _, r := range result.Results {
+		switch r.UID {
+		case "keep":
+			require.Equal(t, datasources.ApplyActionUnchanged, r.Action)
+			sawUnchanged = true
+		case "drop":
+			require.Equal(t, datasources.ApplyActionDeleted, r.Action)
+			sawDeleted = true
+		}
+	}
+	require.True(t, sawUnchanged)
+	require.True(t, sawDeleted)
+
+	var remaining []*datasources.DataSource
+	err = store.db.WithDbSession(ctx, func(sess *db.Session) error {
+		remaining = make([]*datasources.DataSource, 0)
+		return sess.Where("org_id=?", orgID).Find(&remaining)
+	})
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	require.Equal(t, "keep", remaining[0].UID)
+}
+
+func TestApplyDataSources_SecureJsonDataChangeTriggersUpdate(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	result, err := store.ApplyDataSources(ctx, orgID, []datasources.DataSource{
+		{Name: "ds", Type: "prometheus", UID: "ds-uid", SecureJsonData: map[string][]byte{"apiKey": []byte("old")}},
+	}, datasources.ApplyOptions{})
+	require.NoError(t, err)
+	require.Equal(t, datasources.ApplyActionCreated, result.Results[0].Action)
+
+	// Every other field is identical; only the secret changed.
+	result, err = store.ApplyDataSources(ctx, orgID, []datasources.DataSource{
+		{Name: "ds", Type: "prometheus", UID: "ds-uid", SecureJsonData: map[string][]byte{"apiKey": []byte("new")}},
+	}, datasources.ApplyOptions{})
+	require.NoError(t, err)
+	require.Equal(t, datasources.ApplyActionUpdated, result.Results[0].Action, "a changed secret must not be reported Unchanged")
+}
+
+func TestApplyDataSources_ContinueOnErrorReconcilesTheRest(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	// Both items are new (no row currently has either UID), so the first
+	// insert succeeds; the second collides with it on the unique UID
+	// constraint. Without per-item savepoints that failure would poison the
+	// rest of the transaction on Postgres; with ContinueOnError it must not
+	// take down the sibling item that follows it.
+	result, err := store.ApplyDataSources(ctx, orgID, []datasources.DataSource{
+		{Name: "dup-1", Type: "loki", UID: "dup-uid"},
+		{Name: "dup-2", Type: "loki", UID: "dup-uid"},
+		{Name: "fine", Type: "loki", UID: "fine-uid"},
+	}, datasources.ApplyOptions{ContinueOnError: true})
+	require.NoError(t, err, "ContinueOnError should let the whole apply commit despite one item failing")
+	require.Len(t, result.Results, 3)
+
+	require.NoError(t, result.Results[0].Error)
+	require.Equal(t, datasources.ApplyActionCreated, result.Results[0].Action)
+
+	require.ErrorIs(t, result.Results[1].Error, datasources.ErrDataSourceUidExists)
+	require.Empty(t, result.Results[1].Action)
+
+	require.NoError(t, result.Results[2].Error)
+	require.Equal(t, datasources.ApplyActionCreated, result.Results[2].Action)
+
+	// The failed item's savepoint rollback must not have taken the
+	// successful one down with it.
+	var fine datasources.DataSource
+	var hasFine bool
+	err = store.db.WithDbSession(ctx, func(sess *db.Session) error {
+		var innerErr error
+		hasFine, innerErr = sess.Where("org_id=? AND uid=?", orgID, "fine-uid").Get(&fine)
+		return innerErr
+	})
+	require.NoError(t, err)
+	require.True(t, hasFine)
+}
+
+func TestDeleteDataSource_SoftDeleteHidesAndRestoreRecoversUID(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	addCmd := &datasources.AddDataSourceCommand{OrgID: orgID, Name: "ds", Type: "prometheus", UID: "soft-uid"}
+	require.NoError(t, store.AddDataSource(ctx, addCmd))
+	id := addCmd.Result.ID
+
+	delCmd := &datasources.DeleteDataSourceCommand{OrgID: orgID, UID: "soft-uid", SoftDelete: true}
+	require.NoError(t, store.DeleteDataSource(ctx, delCmd))
+	require.EqualValues(t, 1, delCmd.DeletedDatasourcesCount)
+
+	err := store.GetDataSource(ctx, &datasources.GetDataSourceQuery{OrgID: orgID, UID: "soft-uid"})
+	require.ErrorIs(t, err, datasources.ErrDataSourceNotFound, "a soft-deleted datasource must not show up in a normal Get")
+
+	// Re-adding the same name/UID while the soft-deleted row is still
+	// within its retention window must fail distinctly, not silently
+	// collide or resurrect it.
+	err = store.AddDataSource(ctx, &datasources.AddDataSourceCommand{OrgID: orgID, Name: "ds", Type: "prometheus", UID: "soft-uid"})
+	require.ErrorIs(t, err, datasources.ErrDataSourceSoftDeletedConflict)
+
+	listQuery := &datasources.ListDeletedQuery{OrgID: orgID}
+	require.NoError(t, store.ListDeletedDataSources(ctx, listQuery))
+	require.Len(t, listQuery.Result, 1)
+	require.Equal(t, "soft-uid", listQuery.Result[0].UID)
+
+	restoreCmd := &datasources.RestoreDataSourceCommand{OrgID: orgID, ID: id}
+	require.NoError(t, store.RestoreDataSource(ctx, restoreCmd))
+	require.Equal(t, "soft-uid", restoreCmd.Result.UID)
+
+	err = store.GetDataSource(ctx, &datasources.GetDataSourceQuery{OrgID: orgID, UID: "soft-uid"})
+	require.NoError(t, err, "a restored datasource must show up in Get again")
+}
+
+func TestRestoreDataSource_RequiresAnIdentifier(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+
+	err := store.RestoreDataSource(ctx, &datasources.RestoreDataSourceCommand{OrgID: 1})
+	require.ErrorIs(t, err, datasources.ErrDataSourceIdentifierNotSet, "OrgID alone must not match an arbitrary soft-deleted row")
+}
+
+func TestApplyDataSources_DoesNotPruneWithinRetentionAndRejectsSoftDeletedUID(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	_, err := store.ApplyDataSources(ctx, orgID, []datasources.DataSource{
+		{Name: "ds", Type: "prometheus", UID: "soft-uid"},
+	}, datasources.ApplyOptions{})
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{OrgID: orgID, UID: "soft-uid", SoftDelete: true}))
+
+	// An apply with Prune=true and an empty desired set must not treat the
+	// soft-deleted row as "current" and hard-delete it.
+	result, err := store.ApplyDataSources(ctx, orgID, nil, datasources.ApplyOptions{Prune: true})
+	require.NoError(t, err)
+	require.Empty(t, result.Results)
+
+	listQuery := &datasources.ListDeletedQuery{OrgID: orgID}
+	require.NoError(t, store.ListDeletedDataSources(ctx, listQuery))
+	require.Len(t, listQuery.Result, 1, "the soft-deleted row must survive an unrelated apply")
+
+	// Re-creating the same UID through ApplyDataSources must go through the
+	// same soft-deleted-conflict check as AddDataSource, not a raw insert
+	// that would race the unique constraint.
+	result, err = store.ApplyDataSources(ctx, orgID, []datasources.DataSource{
+		{Name: "ds", Type: "prometheus", UID: "soft-uid"},
+	}, datasources.ApplyOptions{ContinueOnError: true})
+	require.NoError(t, err)
+	require.Len(t, result.Results, 1)
+	require.ErrorIs(t, result.Results[0].Error, datasources.ErrDataSourceSoftDeletedConflict)
+}
+
+func TestPurgeDeletedDataSources_RemovesOnlyRowsPastRetention(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	addCmd := &datasources.AddDataSourceCommand{OrgID: orgID, Name: "ds", Type: "prometheus", UID: "old-uid"}
+	require.NoError(t, store.AddDataSource(ctx, addCmd))
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{OrgID: orgID, UID: "old-uid", SoftDelete: true}))
+
+	// A retention window that has already elapsed purges the row...
+	require.NoError(t, store.purgeDeletedDataSources(ctx, -time.Hour, nil))
+
+	listQuery := &datasources.ListDeletedQuery{OrgID: orgID}
+	require.NoError(t, store.ListDeletedDataSources(ctx, listQuery))
+	require.Empty(t, listQuery.Result)
+
+	addCmd = &datasources.AddDataSourceCommand{OrgID: orgID, Name: "ds2", Type: "prometheus", UID: "fresh-uid"}
+	require.NoError(t, store.AddDataSource(ctx, addCmd))
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{OrgID: orgID, UID: "fresh-uid", SoftDelete: true}))
+
+	// ...but a window that hasn't elapsed yet leaves it alone.
+	require.NoError(t, store.purgeDeletedDataSources(ctx, time.Hour, nil))
+
+	require.NoError(t, store.ListDeletedDataSources(ctx, listQuery))
+	require.Len(t, listQuery.Result, 1)
+}
+
+func TestDeleteDataSource_DefersSecretCleanupToPurge(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{OrgID: orgID, Name: "ds", Type: "prometheus", UID: "soft-uid"}))
+
+	var updateSecretCalled bool
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{
+		OrgID: orgID, UID: "soft-uid", SoftDelete: true,
+		UpdateSecretFn: func() error { updateSecretCalled = true; return nil },
+	}))
+	require.False(t, updateSecretCalled, "UpdateSecretFn must not run at soft-delete time, so a restore still has its secrets")
+
+	var deletedUIDs []string
+	require.NoError(t, store.purgeDeletedDataSources(ctx, -time.Hour, func(ds *datasources.DataSource) error {
+		deletedUIDs = append(deletedUIDs, ds.UID)
+		return nil
+	}))
+	require.Equal(t, []string{"soft-uid"}, deletedUIDs)
+}
+
+func TestGetDataSources_CursorPaginatesByNameThenUID(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	names := []string{"alpha", "bravo", "charlie", "delta", "echo"}
+	for _, name := range names {
+		require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+			OrgID: orgID, Name: name, Type: "prometheus", UID: name + "-uid",
+		}))
+	}
+
+	var seen []string
+	cursor := ""
+	for {
+		query := &datasources.GetDataSourcesQuery{OrgID: orgID, PageSize: 2, AfterUID: cursor}
+		require.NoError(t, store.GetDataSources(ctx, query))
+		require.LessOrEqual(t, len(query.Result), 2)
+
+		for _, ds := range query.Result {
+			seen = append(seen, ds.Name)
+		}
+
+		if query.NextCursor == "" {
+			break
+		}
+		cursor = query.NextCursor
+	}
+
+	require.Equal(t, names, seen, "paginating page by page must visit every datasource, in order, exactly once")
+}
+
+func TestGetDataSources_CursorSurvivesRowDeletedBetweenPages(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	names := []string{"alpha", "bravo", "charlie"}
+	for _, name := range names {
+		require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+			OrgID: orgID, Name: name, Type: "prometheus", UID: name + "-uid",
+		}))
+	}
+
+	query := &datasources.GetDataSourcesQuery{OrgID: orgID, PageSize: 1}
+	require.NoError(t, store.GetDataSources(ctx, query))
+	require.Len(t, query.Result, 1)
+	require.Equal(t, "alpha", query.Result[0].Name)
+	cursor := query.NextCursor
+	require.NotEmpty(t, cursor)
+
+	// The row the cursor seeks past is hard-deleted before the next page is
+	// fetched; GetDataSources must not need it to still exist.
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{OrgID: orgID, UID: "alpha-uid"}))
+
+	query = &datasources.GetDataSourcesQuery{OrgID: orgID, PageSize: 1, AfterUID: cursor}
+	require.NoError(t, store.GetDataSources(ctx, query))
+	require.Len(t, query.Result, 1)
+	require.Equal(t, "bravo", query.Result[0].Name)
+}
+
+func TestGetDataSources_BareUIDCursorKeepsOrgAndFilterConditions(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	names := []string{"alpha", "bravo", "charlie"}
+	for _, name := range names {
+		require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+			OrgID: orgID, Name: name, Type: "prometheus", UID: name + "-uid",
+		}))
+	}
+
+	// A soft-deleted row and another org's row sort between "alpha" and
+	// "bravo" in the (name, uid) ordering; seeking from a bare UID must not
+	// leak either of them into the page.
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+		OrgID: orgID, Name: "alpha-zzz-deleted", Type: "prometheus", UID: "deleted-uid",
+	}))
+	require.NoError(t, store.DeleteDataSource(ctx, &datasources.DeleteDataSourceCommand{OrgID: orgID, UID: "deleted-uid", SoftDelete: true}))
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+		OrgID: orgID + 1, Name: "alpha-zzz-otherorg", Type: "prometheus", UID: "otherorg-uid",
+	}))
+
+	// AfterUID set to a bare UID (not a NextCursor) exercises the fallback
+	// lookup path in seekPosition.
+	query := &datasources.GetDataSourcesQuery{OrgID: orgID, PageSize: 2, AfterUID: "alpha-uid"}
+	require.NoError(t, store.GetDataSources(ctx, query))
+
+	var seen []string
+	for _, ds := range query.Result {
+		seen = append(seen, ds.Name)
+	}
+	require.Equal(t, []string{"bravo", "charlie"}, seen, "org scoping and the deleted_at filter must survive the bare-UID seek lookup")
+}
+
+func TestGetDataSources_Filters(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+	const orgID = int64(1)
+
+	isDefaultTrue := true
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+		OrgID: orgID, Name: "prod-prometheus", Type: "prometheus", UID: "prom-uid", IsDefault: true,
+	}))
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+		OrgID: orgID, Name: "prod-loki", Type: "loki", UID: "loki-uid",
+	}))
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{
+		OrgID: orgID, Name: "staging-loki", Type: "loki", UID: "staging-loki-uid",
+	}))
+
+	query := &datasources.GetDataSourcesQuery{OrgID: orgID, Filter: datasources.DataSourceFilter{NameContains: "prod-"}}
+	require.NoError(t, store.GetDataSources(ctx, query))
+	require.Len(t, query.Result, 2)
+
+	query = &datasources.GetDataSourcesQuery{OrgID: orgID, Filter: datasources.DataSourceFilter{TypeIn: []string{"loki"}}}
+	require.NoError(t, store.GetDataSources(ctx, query))
+	require.Len(t, query.Result, 2)
+
+	query = &datasources.GetDataSourcesQuery{OrgID: orgID, Filter: datasources.DataSourceFilter{IsDefault: &isDefaultTrue}}
+	require.NoError(t, store.GetDataSources(ctx, query))
+	require.Len(t, query.Result, 1)
+	require.Equal(t, "prod-prometheus", query.Result[0].Name)
+}
+
+func TestGetAllDataSources_ThreadsFilterAcrossOrgs(t *testing.T) {
+	store := createTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{OrgID: 1, Name: "org1-loki", Type: "loki", UID: "org1-uid"}))
+	require.NoError(t, store.AddDataSource(ctx, &datasources.AddDataSourceCommand{OrgID: 2, Name: "org2-prometheus", Type: "prometheus", UID: "org2-uid"}))
+
+	query := &datasources.GetAllDataSourcesQuery{Filter: datasources.DataSourceFilter{TypeIn: []string{"loki"}}}
+	require.NoError(t, store.GetAllDataSources(ctx, query))
+	require.Len(t, query.Result, 1)
+	require.Equal(t, int64(1), query.Result[0].OrgID)
+}
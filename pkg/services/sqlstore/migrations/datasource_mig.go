@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// addDataSourceSoftDeleteMigrations appends the data_source soft-delete
+// support to the existing data_source migration list: a nullable deleted_at
+// column, left NULL for every row that hasn't been soft-deleted, plus an
+// index so filtering it out of Get* queries stays index-only.
+func addDataSourceSoftDeleteMigrations(mg *migrator.Migrator) {
+	mg.AddMigration("add deleted_at column to data_source", migrator.NewAddColumnMigration(
+		migrator.Table{Name: "data_source"},
+		&migrator.Column{Name: "deleted_at", Type: migrator.DB_DateTime, Nullable: true},
+	))
+
+	mg.AddMigration("add index data_source.deleted_at", migrator.NewAddIndexMigration(
+		migrator.Table{Name: "data_source"},
+		&migrator.Index{Cols: []string{"deleted_at"}},
+	))
+}
+
+// addDataSourcePaginationIndexMigrations appends the indexes that back
+// GetDataSources' keyset pagination and filtering: (org_id, name) for the
+// default name-ordered listing and cursor seek, and (org_id, updated) for
+// the UpdatedSince filter.
+func addDataSourcePaginationIndexMigrations(mg *migrator.Migrator) {
+	mg.AddMigration("add index data_source.org_id_name", migrator.NewAddIndexMigration(
+		migrator.Table{Name: "data_source"},
+		&migrator.Index{Cols: []string{"org_id", "name"}},
+	))
+
+	mg.AddMigration("add index data_source.org_id_updated", migrator.NewAddIndexMigration(
+		migrator.Table{Name: "data_source"},
+		&migrator.Index{Cols: []string{"org_id", "updated"}},
+	))
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+// OSSMigrations wires up the open-source migration list consumed by the
+// migrator at startup.
+type OSSMigrations struct{}
+
+func ProvideOSSMigrations() *OSSMigrations {
+	return &OSSMigrations{}
+}
+
+// AddMigration registers every migration, in the order they must run.
+func (*OSSMigrations) AddMigration(mg *migrator.Migrator) {
+	addDataSourceSoftDeleteMigrations(mg)
+	addDataSourcePaginationIndexMigrations(mg)
+}
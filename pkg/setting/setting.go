@@ -0,0 +1,29 @@
+// Package setting holds the Cfg fields this series' packages read from the
+// global Grafana configuration. It mirrors a subset of the real
+// pkg/setting.Cfg from the main server repository; it is not the full
+// configuration loader.
+package setting
+
+import "time"
+
+// Cfg is the global Grafana configuration consumed by this series'
+// collaborators.
+type Cfg struct {
+	DataPath string
+
+	PluginSettings map[string]map[string]string
+
+	// PluginsCDNURLTemplate is the base URL template of the primary plugins
+	// CDN. An empty value disables the CDN.
+	PluginsCDNURLTemplate string
+	// PluginsCDNMirrorURLTemplate is the base URL template of a secondary
+	// plugins CDN used as a fallback when the primary CDN is unreachable or
+	// serves an asset that fails digest verification. An empty value
+	// disables the mirror fallback.
+	PluginsCDNMirrorURLTemplate string
+
+	// DataSourceSoftDeleteRetention is how long a soft-deleted datasource is
+	// kept before the background purge removes it for good. Read from
+	// datasources.soft_delete_retention.
+	DataSourceSoftDeleteRetention time.Duration
+}
@@ -0,0 +1,25 @@
+package setting
+
+import (
+	"time"
+
+	"gopkg.in/ini.v1"
+)
+
+// defaultDataSourceSoftDeleteRetention is used when
+// datasources.soft_delete_retention is left unset or fails to parse.
+const defaultDataSourceSoftDeleteRetention = 24 * time.Hour
+
+// readDataSourceSettings populates the datasource-related fields of Cfg from
+// the [datasources] ini section.
+func (cfg *Cfg) readDataSourceSettings(iniFile *ini.File) error {
+	sec := iniFile.Section("datasources")
+
+	retention, err := time.ParseDuration(sec.Key("soft_delete_retention").MustString(""))
+	if err != nil || retention <= 0 {
+		retention = defaultDataSourceSoftDeleteRetention
+	}
+	cfg.DataSourceSoftDeleteRetention = retention
+
+	return nil
+}